@@ -3,19 +3,44 @@ package main
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
-	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Rehtt/Kit/util/size"
-	"github.com/gogf/gf/v2/util/gconv"
 )
 
+// backupTimeFormat 是轮转文件名中携带的时间戳格式，取代原先的 "<name>.<N>" 自增序号，
+// 使轮转文件的新旧顺序可以直接从文件名解析出来，无需扫描目录维护全局计数器。
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// tmpLogfileSuffix 沿用 Docker 自身 jsonfilelog 的做法：轮转时先把旧文件重命名为
+// "<backup>.tmp"，压缩 worker 处理完成后再落地成最终的 ".gz" 文件，
+// 这样即使进程在压缩过程中崩溃，也能在下次启动时通过该后缀找回未完成的任务。
+const tmpLogfileSuffix = ".tmp"
+
+// rotationMeta 记录一次轮转的时间信息，编码进压缩文件的 gzip Comment/Extra 字段，
+// 使下游工具无需解压即可判断这个归档大致覆盖的时间范围。
+type rotationMeta struct {
+	RotatedAt    time.Time `json:"rotated_at"`
+	LastLineTime time.Time `json:"last_line_time,omitempty"`
+}
+
+// compressJob 是一次待压缩的轮转任务。
+type compressJob struct {
+	tmpPath      string
+	gzPath       string
+	rotatedAt    time.Time
+	lastLineTime time.Time
+}
+
 type LogFile struct {
 	mu      sync.RWMutex
 	logfile string
@@ -25,6 +50,40 @@ type LogFile struct {
 	limitSize   uint64
 	closed      bool
 	compression bool
+
+	maxAge     time.Duration
+	maxBackups int
+	localTime  bool
+
+	// serviceName/rootDir 是构造时确定的固定信息：rootDir 是该服务所有日志（含按时间
+	// 分层的子目录）的根目录，不会随轮转变化，检索/清理都基于它递归展开。
+	serviceName string
+	rootDir     string
+
+	// rotateInterval 非 0 时，timeRotateLoop 会在每个时间边界强制轮转一次，
+	// 与按大小触发的轮转互相独立、谁先到谁触发。
+	rotateInterval time.Duration
+
+	// filenamePattern 非空时启用按时间分层的目录布局（strftime 占位符 + {name} 服务名占位符），
+	// 活跃文件路径随时间推进而变化；为空则沿用扁平的 "<service>/<service>.log" 布局。
+	filenamePattern string
+
+	// stopCh 在 Close 时关闭，让 cleanupLoop/compressLoop/timeRotateLoop 这几个后台
+	// goroutine 及时退出，避免容器停止监控后它们永远阻塞在 channel 上，泄漏 goroutine
+	// 和它们引用的 *LogFile。
+	stopCh chan struct{}
+
+	// cleanupCh 用于把过期/超量清理串行到单个后台 goroutine 上执行，
+	// 避免并发的清理任务互相竞争同一批文件。
+	cleanupCh chan struct{}
+
+	// compressCh 把压缩任务交给后台 goroutine 处理，轮转本身只需要一次
+	// 文件重命名，不再阻塞日志写入。
+	compressCh chan compressJob
+
+	// lastLineTime 是最近一次成功写入的日志行携带的时间戳（由 Docker 前缀到每行开头），
+	// 轮转时作为元数据一并写入压缩文件，便于下游工具定位时间范围。
+	lastLineTime time.Time
 }
 
 func (l *LogFile) Write(p []byte) (n int, err error) {
@@ -51,6 +110,9 @@ func (l *LogFile) Write(p []byte) (n int, err error) {
 	// 如果当前写入不会超过限制，直接写入
 	if size := uint64(len(p)); l.size+size <= l.limitSize {
 		n, err = l.f.Write(p)
+		if t, ok := lastLineTimestamp(p); ok {
+			l.lastLineTime = t
+		}
 		return
 	}
 
@@ -68,11 +130,34 @@ func (l *LogFile) Write(p []byte) (n int, err error) {
 			slog.Error("写入日志数据失败", "logfile", l.logfile, "error", err)
 			return n, err
 		}
+		if t, ok := lastLineTimestamp(data); ok {
+			l.lastLineTime = t
+		}
 		n += nn
 	}
 	return
 }
 
+// lastLineTimestamp 从写入的数据中取出最后一行，解析出 Docker 前缀的 RFC3339Nano 时间戳。
+func lastLineTimestamp(p []byte) (time.Time, bool) {
+	trimmed := bytes.TrimRight(p, "\n")
+	line := trimmed
+	if idx := bytes.LastIndexByte(trimmed, '\n'); idx >= 0 {
+		line = trimmed[idx+1:]
+	}
+
+	sp := bytes.IndexByte(line, ' ')
+	if sp <= 0 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, string(line[:sp]))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 func (l *LogFile) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -82,6 +167,7 @@ func (l *LogFile) Close() error {
 	}
 
 	l.closed = true
+	close(l.stopCh)
 
 	if l.f != nil {
 		if err := l.f.Close(); err != nil {
@@ -94,64 +180,81 @@ func (l *LogFile) Close() error {
 }
 
 func (l *LogFile) newFile() (err error) {
+	// filenamePattern 非空时，活跃文件路径本身会随时间推进而变化（落到不同的时间分层目录里）；
+	// target 与当前 l.logfile 不同即说明跨过了一个时间边界。
+	target := l.logfile
+	if l.filenamePattern != "" {
+		target = filepath.Join(*logPath, renderFilenamePattern(l.filenamePattern, l.serviceName, time.Now()))
+	}
+
 	if l.f != nil {
 		if err = l.f.Close(); err != nil {
 			slog.Error("关闭当前日志文件失败", "logfile", l.logfile, "error", err)
 			return
 		}
 
-		// 查找下一个可用的文件索引
-		var maxIndex int
-		dir := filepath.Dir(l.logfile)
-		baseName := filepath.Base(l.logfile)
-
-		if err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
+		if target == l.logfile {
+			// 目标路径没变（同一个时间段内由大小触发的轮转，或未设置 filenamePattern 时的按时间轮转），
+			// 按照 lumberjack 的风格把时间戳编码进轮转文件名
+			newName := backupName(l.logfile, l.localTime, time.Now())
+			if l.compression {
+				// 压缩是异步的：这里只做一次重命名把旧文件让出来，真正的 gzip
+				// 交给后台 worker，避免把压缩耗时压到日志写入路径上。
+				tmpName := newName + tmpLogfileSuffix
+				if err = os.Rename(l.logfile, tmpName); err != nil {
+					slog.Error("重命名日志文件失败", "from", l.logfile, "to", tmpName, "error", err)
+					return fmt.Errorf("rename %s to %s error: %v", l.logfile, tmpName, err)
+				}
+				l.enqueueCompress(compressJob{
+					tmpPath:      tmpName,
+					gzPath:       newName + ".gz",
+					rotatedAt:    time.Now(),
+					lastLineTime: l.lastLineTime,
+				})
+				newName = newName + ".gz"
+			} else {
+				if err = os.Rename(l.logfile, newName); err != nil {
+					slog.Error("重命名日志文件失败", "from", l.logfile, "to", newName, "error", err)
+					return fmt.Errorf("rename %s to %s error: %v", l.logfile, newName, err)
+				}
 			}
 
-			// 检查是否是当前日志文件的轮转文件
-			if suffix, fond := strings.CutPrefix(d.Name(), baseName+"."); fond {
-				if index := gconv.Int(suffix); index > 0 {
-					maxIndex = max(maxIndex, index)
+			slog.Info("日志文件轮转完成", "from", l.logfile, "to", newName)
+		} else {
+			// 跨过了时间边界：新文件名本身已经带上了它覆盖的时间段，旧文件无需改名，
+			// 按需压缩即可。
+			if l.compression {
+				tmpName := l.logfile + tmpLogfileSuffix
+				if err = os.Rename(l.logfile, tmpName); err != nil {
+					slog.Error("重命名日志文件失败", "from", l.logfile, "to", tmpName, "error", err)
+					return fmt.Errorf("rename %s to %s error: %v", l.logfile, tmpName, err)
 				}
+				l.enqueueCompress(compressJob{
+					tmpPath:      tmpName,
+					gzPath:       l.logfile + ".gz",
+					rotatedAt:    time.Now(),
+					lastLineTime: l.lastLineTime,
+				})
 			}
-			return nil
-		}); err != nil {
-			slog.Error("遍历目录失败", "dir", dir, "error", err)
-			return fmt.Errorf("walk dir %s error: %v", dir, err)
-		}
-
-		// 重命名当前文件
-		newName := fmt.Sprintf("%s.%d", l.logfile, maxIndex+1)
-		if l.compression {
-			newName = newName + ".gz"
-			f, err := os.OpenFile(newName, os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				slog.Error("创建新日志文件失败", "logfile", newName, "error", err)
-				return fmt.Errorf("create log file %s error: %v", newName, err)
-			}
-			defer f.Close()
-			g := gzip.NewWriter(f)
-			defer g.Close()
-			oldLog, err := os.Open(l.logfile)
-			if err != nil {
-				slog.Error("打开日志文件失败", "logfile", l.logfile, "error", err)
-				return fmt.Errorf("open log file %s error: %v", l.logfile, err)
-			}
-			defer oldLog.Close()
-			io.Copy(g, oldLog)
-		} else {
-			if err = os.Rename(l.logfile, newName); err != nil {
-				slog.Error("重命名日志文件失败", "from", l.logfile, "to", newName, "error", err)
-				return fmt.Errorf("rename %s to %s error: %v", l.logfile, newName, err)
+
+			slog.Info("日志文件按时间边界轮转完成", "from", l.logfile, "to", target)
+		}
+
+		// 触发一次清理，串行到同一个后台 goroutine 上执行
+		if l.cleanupCh != nil {
+			select {
+			case l.cleanupCh <- struct{}{}:
+			default:
 			}
 		}
+	}
 
-		slog.Info("日志文件轮转完成", "from", l.logfile, "to", newName)
+	l.logfile = target
+	if dir := filepath.Dir(l.logfile); dir != l.rootDir {
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			slog.Error("创建日志目录失败", "dir", dir, "error", err)
+			return fmt.Errorf("create log dir %s error: %v", dir, err)
+		}
 	}
 
 	// 创建新的日志文件
@@ -172,27 +275,359 @@ func (l *LogFile) newFile() (err error) {
 	return
 }
 
-func NewLogFile(serviceName string, limitSize size.ByteSize, compression bool) (*LogFile, error) {
-	dir := filepath.Join(*logPath, serviceName)
-	err := os.MkdirAll(dir, 0o755)
-	if err != nil {
-		slog.Error("创建日志目录失败", "dir", dir, "error", err)
-		return nil, fmt.Errorf("create log dir %s error: %v", dir, err)
+// NewLogFile 创建一个按大小轮转的日志文件，可以叠加按时间边界的强制轮转。
+// maxAge 为 0 表示不按时间清理历史文件，maxBackups 为 0 表示不限制历史文件数量；
+// localTime 控制轮转文件名中的时间戳使用本地时间还是 UTC（默认 UTC，与 lumberjack 保持一致）。
+// rotateInterval 为 0 表示不按时间强制轮转，否则每到一个时间边界就强制 newFile()，
+// 与大小触发的轮转互相独立、谁先到谁触发；filenamePattern 非空时启用按时间分层的目录布局
+// （strftime 占位符 + {name} 服务名占位符），为空则沿用扁平的 "<service>/<service>.log" 布局。
+func NewLogFile(serviceName string, limitSize size.ByteSize, compression bool, maxAge time.Duration, maxBackups int, localTime bool, rotateInterval time.Duration, filenamePattern string) (*LogFile, error) {
+	rootDir := filepath.Join(*logPath, serviceName)
+	if err := os.MkdirAll(rootDir, 0o755); err != nil {
+		slog.Error("创建日志目录失败", "dir", rootDir, "error", err)
+		return nil, fmt.Errorf("create log dir %s error: %v", rootDir, err)
+	}
+
+	logfile := filepath.Join(rootDir, serviceName+".log")
+	if filenamePattern != "" {
+		logfile = filepath.Join(*logPath, renderFilenamePattern(filenamePattern, serviceName, time.Now()))
 	}
 
-	logfile := filepath.Join(dir, serviceName+".log")
 	l := &LogFile{
-		logfile:     logfile,
-		limitSize:   uint64(limitSize),
-		closed:      false,
-		compression: compression,
+		logfile:         logfile,
+		serviceName:     serviceName,
+		rootDir:         rootDir,
+		stopCh:          make(chan struct{}),
+		limitSize:       uint64(limitSize),
+		closed:          false,
+		compression:     compression,
+		maxAge:          maxAge,
+		maxBackups:      maxBackups,
+		localTime:       localTime,
+		rotateInterval:  rotateInterval,
+		filenamePattern: filenamePattern,
 	}
 
-	slog.Debug("创建日志文件", "service", serviceName, "logfile", logfile, "limit", limitSize)
+	slog.Debug("创建日志文件", "service", serviceName, "logfile", logfile, "limit", limitSize,
+		"maxAge", maxAge, "maxBackups", maxBackups, "rotateInterval", rotateInterval, "filenamePattern", filenamePattern)
+
+	if compression {
+		l.compressCh = make(chan compressJob, 4)
+		go l.compressLoop()
+		if err := l.recoverTmpFiles(); err != nil {
+			slog.Error("恢复未完成的压缩任务失败", "dir", rootDir, "error", err)
+		}
+	}
 
 	if err := l.newFile(); err != nil {
 		return nil, err
 	}
 
+	if maxAge > 0 || maxBackups > 0 {
+		l.cleanupCh = make(chan struct{}, 1)
+		go l.cleanupLoop()
+	}
+
+	if rotateInterval > 0 {
+		go l.timeRotateLoop()
+	}
+
 	return l, nil
 }
+
+// recoverTmpFiles 在启动时找回崩溃前未压缩完成的 ".tmp" 文件，重新交给压缩 worker 处理，
+// 避免进程重启后留下一堆永远不会被压缩的备份文件。rootDir 下可能存在按时间分层的子目录，
+// 因此递归遍历整棵树而不是只看一层。".tmp" 后缀本身就是 newFile() 轮转时产生的标记，
+// 不需要再按 backupTimeFormat 校验文件名——按时间边界轮转出来的 tmp 文件并不带那个后缀。
+func (l *LogFile) recoverTmpFiles() error {
+	err := filepath.WalkDir(l.rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), tmpLogfileSuffix) {
+			return nil
+		}
+
+		backupFileName := strings.TrimSuffix(d.Name(), tmpLogfileSuffix)
+		rotatedAt := time.Now()
+		if info, err := d.Info(); err == nil {
+			rotatedAt = info.ModTime()
+		}
+
+		slog.Warn("发现未压缩完成的临时文件，重新加入压缩队列", "file", path)
+		l.compressCh <- compressJob{
+			tmpPath:   path,
+			gzPath:    filepath.Join(filepath.Dir(path), backupFileName+".gz"),
+			rotatedAt: rotatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk dir %s error: %v", l.rootDir, err)
+	}
+	return nil
+}
+
+// strftimeTokens 把 FilenamePattern 支持的 strftime 风格占位符转换成 time.Format 能识别的参考布局。
+var strftimeTokens = strings.NewReplacer(
+	"%Y", "2006",
+	"%m", "01",
+	"%d", "02",
+	"%H", "15",
+	"%M", "04",
+	"%S", "05",
+)
+
+// renderFilenamePattern 先按 t 渲染 pattern 中的 strftime 占位符，再把 {name} 替换成服务名（服务名不经过 time.Format），
+// 得到形如 "<service>/20250101/<service>-20250101-15.log" 的相对路径（相对于 *logPath）。
+func renderFilenamePattern(pattern, serviceName string, t time.Time) string {
+	rendered := t.Format(strftimeTokens.Replace(pattern))
+	return strings.ReplaceAll(rendered, "{name}", serviceName)
+}
+
+// parseRotateInterval 解析 -rotate-interval：除了 hourly/daily 两个别名，其余按
+// time.ParseDuration 解析（如 "10m"、"1h"），空字符串表示不启用按时间轮转。
+func parseRotateInterval(raw string) (time.Duration, error) {
+	switch raw {
+	case "":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+// nextRotateBoundary 返回下一个时间轮转边界：把 now 截断到 interval 的整数倍再加一个
+// interval，这样 hourly/daily 能落在整点/整天上，而不是从进程启动时刻开始计时。
+func nextRotateBoundary(now time.Time, interval time.Duration) time.Time {
+	return now.Truncate(interval).Add(interval)
+}
+
+// timeRotateLoop 按 rotateInterval 在每个时间边界强制轮转一次，即使还没达到大小限制；
+// 与 filenamePattern 搭配可以把日志落到按时间分层的目录里。select 同时监听 stopCh，
+// 这样 Close 能立即唤醒它退出，而不必等到睡完整个 rotateInterval 才发现自己该停了。
+func (l *LogFile) timeRotateLoop() {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(nextRotateBoundary(now, l.rotateInterval).Sub(now))
+
+		select {
+		case <-l.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		l.mu.Lock()
+		if !l.closed {
+			if err := l.newFile(); err != nil {
+				slog.Error("按时间轮转日志文件失败", "logfile", l.logfile, "error", err)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// enqueueCompress 把压缩任务非阻塞地交给后台 worker。newFile 在持有 l.mu 的临界区内调用
+// 它，如果 compressCh 已满（压缩 worker 跟不上轮转速度）就丢弃并告警，而不是阻塞住
+// Write()，否则压缩又会变回拖慢日志写入的那个老问题。
+func (l *LogFile) enqueueCompress(job compressJob) {
+	select {
+	case l.compressCh <- job:
+	default:
+		slog.Warn("压缩队列已满，丢弃本次轮转的压缩任务", "file", job.tmpPath)
+	}
+}
+
+// compressLoop 串行地把轮转出来的临时文件压缩为 gzip 归档；同时监听 stopCh，
+// Close 之后立即退出而不是永远阻塞在 compressCh 上等待下一次轮转。
+func (l *LogFile) compressLoop() {
+	for {
+		select {
+		case job := <-l.compressCh:
+			if err := compressRotatedFile(job); err != nil {
+				slog.Error("压缩轮转日志文件失败", "file", job.tmpPath, "error", err)
+			}
+		case <-l.stopCh:
+			return
+		}
+	}
+}
+
+// compressRotatedFile 把 job.tmpPath 压缩为 job.gzPath，并在 gzip 的 Comment/Extra 字段中
+// 附带一份 JSON 元数据，记录轮转时间和最后一行日志的时间，完成后 fsync 并删除临时文件。
+func compressRotatedFile(job compressJob) error {
+	dst, err := os.OpenFile(job.gzPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create gz file %s error: %v", job.gzPath, err)
+	}
+	defer dst.Close()
+
+	meta, _ := json.Marshal(rotationMeta{
+		RotatedAt:    job.rotatedAt,
+		LastLineTime: job.lastLineTime,
+	})
+
+	g := gzip.NewWriter(dst)
+	g.ModTime = job.rotatedAt
+	g.Comment = string(meta)
+	g.Extra = meta
+
+	src, err := os.Open(job.tmpPath)
+	if err != nil {
+		g.Close()
+		return fmt.Errorf("open tmp file %s error: %v", job.tmpPath, err)
+	}
+
+	_, copyErr := io.Copy(g, src)
+	src.Close()
+	if copyErr != nil {
+		g.Close()
+		return fmt.Errorf("compress %s error: %v", job.tmpPath, copyErr)
+	}
+
+	if err := g.Close(); err != nil {
+		return fmt.Errorf("close gzip writer for %s error: %v", job.gzPath, err)
+	}
+	if err := dst.Sync(); err != nil {
+		return fmt.Errorf("fsync %s error: %v", job.gzPath, err)
+	}
+	if err := os.Remove(job.tmpPath); err != nil {
+		return fmt.Errorf("remove tmp file %s error: %v", job.tmpPath, err)
+	}
+
+	slog.Info("后台压缩轮转日志文件完成", "from", job.tmpPath, "to", job.gzPath)
+	return nil
+}
+
+// backupName 根据 lumberjack 的命名方式，把轮转时间编码进文件名，
+// 形如 "<name>-2006-01-02T15-04-05.000.log"，这样无需维护全局序号即可推断先后顺序。
+func backupName(name string, local bool, t time.Time) string {
+	dir := filepath.Dir(name)
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext)
+
+	if !local {
+		t = t.UTC()
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext))
+}
+
+// parseBackupTime 尝试从轮转文件名中解析出时间戳，不匹配 logfile 命名规则的文件会被忽略。
+// logfile 是轮转前的活跃日志文件路径，用来推导出 name 应当具有的前缀和扩展名。
+func parseBackupTime(logfile, name string) (time.Time, bool) {
+	base := filepath.Base(logfile)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+
+	rest := strings.TrimSuffix(name, ".gz")
+	rest = strings.TrimSuffix(rest, ext)
+	rest = strings.TrimPrefix(rest, prefix)
+
+	t, err := time.Parse(backupTimeFormat, rest)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+type backupInfo struct {
+	path string
+	t    time.Time
+}
+
+// cleanupLoop 串行地执行清理任务，避免多次轮转同时触发的清理互相竞争同一批文件；
+// 同时监听 stopCh，Close 之后立即退出而不是永远阻塞在 cleanupCh 上等待下一次轮转。
+func (l *LogFile) cleanupLoop() {
+	for {
+		select {
+		case <-l.cleanupCh:
+			if err := l.cleanupBackups(); err != nil {
+				slog.Error("清理历史日志文件失败", "logfile", l.logfile, "error", err)
+			}
+		case <-l.stopCh:
+			return
+		}
+	}
+}
+
+// cleanupBackups 按 MaxAge 删除过期的轮转文件，再按 MaxBackups 删除多余的轮转文件。
+// 压缩和未压缩的轮转文件一并计数，当前活跃文件不受影响。rootDir 下可能存在按时间分层的
+// 子目录（见 filenamePattern），因此递归遍历整棵树而不是只看一层。扁平布局下按大小轮转出来的
+// 文件名能用 parseBackupTime 解析出准确的轮转时间；按时间边界轮转出来的文件，以及启用
+// filenamePattern 后按大小轮转出来的文件，文件名不符合 parseBackupTime 的格式，这类文件退回用
+// mtime 排序，两者统一计入同一批清理。
+func (l *LogFile) cleanupBackups() error {
+	l.mu.RLock()
+	maxAge := l.maxAge
+	maxBackups := l.maxBackups
+	rootDir := l.rootDir
+	canonical := filepath.Join(l.rootDir, l.serviceName+".log")
+	activeLogfile := l.logfile
+	l.mu.RUnlock()
+
+	if maxAge <= 0 && maxBackups <= 0 {
+		return nil
+	}
+
+	var backups []backupInfo
+	walkErr := filepath.WalkDir(rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), tmpLogfileSuffix) || path == activeLogfile {
+			return nil
+		}
+		if t, ok := parseBackupTime(canonical, d.Name()); ok {
+			backups = append(backups, backupInfo{path: path, t: t})
+			return nil
+		}
+		info, ierr := d.Info()
+		if ierr != nil {
+			return ierr
+		}
+		backups = append(backups, backupInfo{path: path, t: info.ModTime()})
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("walk dir %s error: %v", rootDir, walkErr)
+	}
+
+	if maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge)
+		remaining := backups[:0]
+		for _, b := range backups {
+			if b.t.After(cutoff) {
+				remaining = append(remaining, b)
+				continue
+			}
+			if err := os.Remove(b.path); err != nil {
+				slog.Error("删除过期日志文件失败", "file", b.path, "error", err)
+				continue
+			}
+			slog.Info("删除过期日志文件", "file", b.path, "maxAge", maxAge)
+		}
+		backups = remaining
+	}
+
+	if maxBackups > 0 && len(backups) > maxBackups {
+		sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+		for _, b := range backups[maxBackups:] {
+			if err := os.Remove(b.path); err != nil {
+				slog.Error("删除多余日志文件失败", "file", b.path, "error", err)
+				continue
+			}
+			slog.Info("删除多余日志文件", "file", b.path, "maxBackups", maxBackups)
+		}
+	}
+
+	return nil
+}
@@ -3,24 +3,34 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
-	"slices"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Rehtt/Kit/util/size"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
 var (
-	logPath        = flag.String("log-path", "/var/log", "out log path")
-	logLimitSize   = flag.String("limit", "50MB", "log limit size")
-	containerNames = flag.String("container-names", "", "container names. eg: name1,name2")
-	compression    = flag.Bool("compression", false, "log file compression")
+	logPath         = flag.String("log-path", "/var/log", "out log path")
+	logLimitSize    = flag.String("limit", "50MB", "log limit size")
+	containerNames  = flag.String("container-names", "", "container names, supports glob patterns (*, ?). eg: name1,web-*")
+	labelSelector   = flag.String("label", "", "match containers by labels, comma-separated key=value pairs. eg: app=web,env=prod")
+	compression     = flag.Bool("compression", false, "log file compression")
+	maxAge          = flag.Duration("max-age", 0, "max age to retain rotated log files, 0 disables age-based cleanup")
+	maxBackups      = flag.Int("max-backups", 0, "max number of rotated log files to retain, 0 disables count-based cleanup")
+	localTime       = flag.Bool("local-time", false, "use local time instead of UTC in rotated log file names")
+	rotateInterval  = flag.String("rotate-interval", "", "force rotation on a time boundary in addition to size rotation: hourly, daily, or a duration like 10m/1h, empty disables")
+	filenamePattern = flag.String("filename-pattern", "", "strftime-style pattern for time-based rotation (%Y %m %d %H %M %S, plus {name} for the service name), eg '{name}/%Y%m%d/{name}-%Y%m%d-%H.log'; empty keeps the flat <service>/<service>.log layout")
+	serveAddr       = flag.String("serve", "", "http address to expose GET /logs/{container}?since=&until=&tail=&follow=, empty disables")
 )
 
 func main() {
@@ -48,107 +58,299 @@ func main() {
 	}
 	defer c.Close()
 
-	names := strings.Split(*containerNames, ",")
-	if len(names) == 0 || (len(names) == 1 && names[0] == "") {
-		slog.Error("未指定容器名称", "container-names", *containerNames)
+	namePatterns, err := compileNamePatterns(*containerNames)
+	if err != nil {
+		slog.Error("解析容器名称模式失败", "error", err)
+		os.Exit(1)
+	}
+	labels := parseLabelSelector(*labelSelector)
+
+	if len(namePatterns) == 0 && len(labels) == 0 {
+		slog.Error("未指定容器名称或 label 选择器", "container-names", *containerNames, "label", *labelSelector)
 		os.Exit(1)
 	}
 
-	slog.Info("开始监控容器日志", "containers", names, "log-path", *logPath, "limit", *logLimitSize)
+	slog.Info("开始监控容器日志", "container-names", *containerNames, "label", *labelSelector,
+		"log-path", *logPath, "limit", *logLimitSize)
+
+	if *serveAddr != "" {
+		go startLogServer(*serveAddr)
+	}
 
-	// 跟踪每个容器的当前 ID 和取消函数，用于检测容器重启
-	type containerInfo struct {
-		id     string
-		cancel context.CancelFunc
+	sup := &supervisor{
+		cli:           c,
+		limit:         limit,
+		namePatterns:  namePatterns,
+		labelSelector: labels,
+		containers:    make(map[string]*watchedContainer),
 	}
-	var containerInfos sync.Map // map[containerName]*containerInfo
+	sup.run(context.Background())
+}
+
+// watchedContainer 记录当前正在被监控的容器 ID，以及取消其日志采集 goroutine 的函数。
+type watchedContainer struct {
+	id     string
+	cancel context.CancelFunc
+}
+
+// supervisor 通过 Docker 事件流取代轮询，在容器 start/die/destroy 时立即做出响应，
+// 并支持按名称通配符和 label 选择器动态发现容器，思路类似 kubelet 的 ContainerLogManager。
+type supervisor struct {
+	cli           *client.Client
+	limit         size.ByteSize
+	namePatterns  []*regexp.Regexp
+	labelSelector map[string]string
+
+	mu         sync.Mutex
+	containers map[string]*watchedContainer // name -> info
+}
 
+// backoffResetThreshold 是事件流需要稳定运行多久才会把退避时间重置回初始值的阈值，
+// 否则一次早期的重连会让之后任何一次偶发断开都要等满 30s，失去“指数”退避的意义。
+const backoffResetThreshold = time.Minute
+
+// run 先用一次 ContainerList 兜底当前已运行的容器，随后完全依赖事件流驱动，
+// 事件流异常断开时按指数退避重连，重连后重新做一次兜底以补齐错过的事件。
+func (s *supervisor) run(ctx context.Context) {
+	backoff := time.Second
 	for {
-		infos, err := c.ContainerList(context.Background(), container.ListOptions{})
+		s.seed(ctx)
+
+		start := time.Now()
+		err := s.watchEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
-			slog.Error("获取容器列表失败", "error", err)
-			time.Sleep(5 * time.Second) // 等待后重试
-			continue
+			slog.Error("事件流断开，准备重连", "error", err, "backoff", backoff)
 		}
 
-		// 构建当前运行的容器映射
-		runningContainers := make(map[string]string) // map[name]id
-		for _, info := range infos {
-			for _, cname := range info.Names {
-				cname = strings.TrimPrefix(cname, "/")
-				if slices.Contains(names, cname) {
-					runningContainers[cname] = info.ID
-					break
-				}
-			}
+		if time.Since(start) >= backoffResetThreshold {
+			backoff = time.Second
 		}
 
-		// 检查需要监控的每个容器
-		for _, name := range names {
-			newID, isRunning := runningContainers[name]
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
 
-			if !isRunning {
-				// 容器没有运行，如果有旧的监控，取消它
-				if val, exists := containerInfos.Load(name); exists {
-					info := val.(*containerInfo)
-					slog.Info("容器已停止，取消监控", "container", name, "id", info.id[:12])
-					info.cancel()
-					containerInfos.Delete(name)
-				}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// seed 用一次性的容器列表补齐事件流建立之前（或重连期间）可能错过的容器状态变化。
+func (s *supervisor) seed(ctx context.Context) {
+	infos, err := s.cli.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		slog.Error("获取容器列表失败", "error", err)
+		return
+	}
+
+	present := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		for _, cname := range info.Names {
+			name := strings.TrimPrefix(cname, "/")
+			if !matchesContainer(name, info.Labels, s.namePatterns, s.labelSelector) {
 				continue
 			}
+			present[name] = true
+			s.startWatch(name, info.ID)
+			break
+		}
+	}
+
+	// 重连期间消失的容器不会再出现在这份列表里，需要主动 stopWatch，
+	// 否则它们会一直占着 s.containers 里的条目（以及对应的采集 goroutine），
+	// 直到（如果）它们用新 ID 重启。
+	s.mu.Lock()
+	var stale []string
+	for name := range s.containers {
+		if !present[name] {
+			stale = append(stale, name)
+		}
+	}
+	s.mu.Unlock()
+	for _, name := range stale {
+		slog.Info("容器在重连期间消失，停止监控", "container", name)
+		s.stopWatch(name)
+	}
+}
 
-			// 容器正在运行
-			val, exists := containerInfos.Load(name)
+// watchEvents 订阅容器的 start/die/destroy 事件并实时响应，直到流出错或 ctx 被取消。
+func (s *supervisor) watchEvents(ctx context.Context) error {
+	f := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("event", "start"),
+		filters.Arg("event", "die"),
+		filters.Arg("event", "destroy"),
+	)
 
-			if !exists || val.(*containerInfo).id != newID {
-				if exists {
-					info := val.(*containerInfo)
-					slog.Info("检测到容器重启", "container", name, "old_id", info.id[:12], "new_id", newID[:12])
-					info.cancel() // 取消旧的 goroutine
-				} else {
-					slog.Info("发现新容器，开始监控", "container", name, "id", newID[:12])
-				}
-				ctx, cancel := context.WithCancel(context.Background())
-				containerInfos.Store(name, &containerInfo{id: newID, cancel: cancel})
-				go handleWithContext(ctx, name, newID, c, limit)
+	msgs, errs := s.cli.Events(ctx, events.ListOptions{Filters: f})
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-errs:
+			if !ok {
+				return fmt.Errorf("event stream closed")
+			}
+			return err
+		case msg, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("event stream closed")
 			}
+			s.handleEvent(msg)
 		}
+	}
+}
 
-		time.Sleep(10 * time.Second)
+func (s *supervisor) handleEvent(msg events.Message) {
+	name := strings.TrimPrefix(msg.Actor.Attributes["name"], "/")
+	if name == "" {
+		return
+	}
+
+	if !matchesContainer(name, msg.Actor.Attributes, s.namePatterns, s.labelSelector) {
+		return
 	}
+
+	switch msg.Action {
+	case "start":
+		slog.Info("检测到容器启动事件", "container", name, "id", msg.Actor.ID[:12])
+		s.startWatch(name, msg.Actor.ID)
+	case "die", "destroy":
+		slog.Info("检测到容器停止事件", "container", name, "id", msg.Actor.ID[:12], "action", msg.Action)
+		s.stopWatch(name)
+	}
+}
+
+// startWatch 为 name 启动（或在容器重启时切换到新 ID 的）日志采集 goroutine。
+func (s *supervisor) startWatch(name, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info, exists := s.containers[name]; exists {
+		if info.id == id {
+			return
+		}
+		slog.Info("检测到容器重启", "container", name, "old_id", info.id[:12], "new_id", id[:12])
+		info.cancel()
+	} else {
+		slog.Info("发现容器，开始监控", "container", name, "id", id[:12])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.containers[name] = &watchedContainer{id: id, cancel: cancel}
+	go handleWithContext(ctx, name, id, s.cli, s.limit)
+}
+
+// stopWatch 取消 name 对应的日志采集 goroutine，由其 defer 负责关闭 LogFile。
+func (s *supervisor) stopWatch(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, exists := s.containers[name]
+	if !exists {
+		return
+	}
+	slog.Info("容器已停止，取消监控", "container", name, "id", info.id[:12])
+	info.cancel()
+	delete(s.containers, name)
+}
+
+// compileNamePatterns 把逗号分隔的通配符模式（* 匹配任意字符，? 匹配单个字符）编译成正则。
+func compileNamePatterns(raw string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		expr := regexp.QuoteMeta(part)
+		expr = strings.NewReplacer(`\*`, `.*`, `\?`, `.`).Replace(expr)
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("compile name pattern %q error: %v", part, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// parseLabelSelector 把逗号分隔的 key=value 解析为 label 选择器，所有键值对都需要匹配（AND 语义）。
+func parseLabelSelector(raw string) map[string]string {
+	selector := make(map[string]string)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if k, v, ok := strings.Cut(part, "="); ok {
+			selector[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return selector
+}
+
+// matchesContainer 判断一个容器是否被监控：未配置名称模式时名称总是匹配，
+// label 选择器要求 labels 中对应的键值全部相等。
+func matchesContainer(name string, labels map[string]string, namePatterns []*regexp.Regexp, labelSelector map[string]string) bool {
+	if len(namePatterns) > 0 {
+		matched := false
+		for _, p := range namePatterns {
+			if p.MatchString(name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for k, v := range labelSelector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
 }
 
 func handleWithContext(ctx context.Context, name string, id string, c *client.Client, limit size.ByteSize) {
 	slog.Info("开始处理容器日志", "container", name, "id", id[:12])
 
-	logfile, err := NewLogFile(name, limit, *compression)
+	sinks, fileSink, err := buildSinks(name, limit)
 	if err != nil {
-		slog.Error("创建日志文件失败", "container", name, "error", err)
+		slog.Error("初始化 sink 失败", "container", name, "error", err)
 		return
 	}
 	defer func() {
-		if err := logfile.Close(); err != nil {
-			slog.Error("关闭日志文件失败", "container", name, "error", err)
+		for _, s := range sinks {
+			if err := s.Close(); err != nil {
+				slog.Error("关闭 sink 失败", "container", name, "error", err)
+			}
 		}
 	}()
 
-	lastLine, err := ReadLastLine(logfile.f)
-	if err != nil {
-		slog.Warn("读取最后一行失败，从头开始", "container", name, "error", err)
-		lastLine = ""
-	}
-
+	// 只有启用了 file sink 时才知道上次写到哪了，其它 sink 没有本地续传点
 	var since string
-	if lastLine != "" {
-		parts := strings.Split(lastLine, " ")
-		if len(parts) > 0 {
-			timestamp := parts[0]
-			// 解析时间戳并加上1纳秒，避免重复读取最后一行
-			since, err = addNanosecond(timestamp)
-			if err != nil {
-				slog.Warn("解析时间戳失败，使用原时间戳", "timestamp", timestamp, "error", err)
-				since = timestamp
+	if fileSink != nil {
+		lastLine, err := fileSink.lastLine()
+		if err != nil {
+			slog.Warn("读取最后一行失败，从头开始", "container", name, "error", err)
+			lastLine = ""
+		}
+		if lastLine != "" {
+			if parts := strings.SplitN(lastLine, " ", 2); len(parts) > 0 {
+				timestamp := parts[0]
+				// 解析时间戳并加上1纳秒，避免重复读取最后一行
+				since, err = addNanosecond(timestamp)
+				if err != nil {
+					slog.Warn("解析时间戳失败，使用原时间戳", "timestamp", timestamp, "error", err)
+					since = timestamp
+				}
 			}
 		}
 	}
@@ -173,7 +375,10 @@ func handleWithContext(ctx context.Context, name string, id string, c *client.Cl
 		}
 	}()
 
-	_, err = stdcopy.StdCopy(logfile, logfile, r)
+	stdout := &lineWriter{container: name, stream: "stdout", sinks: sinks}
+	stderr := &lineWriter{container: name, stream: "stderr", sinks: sinks}
+
+	_, err = stdcopy.StdCopy(stdout, stderr, r)
 
 	// 检查是否是因为 context 取消
 	if ctx.Err() != nil {
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReadOptions 控制 LogReader 读取历史日志的方式。
+type ReadOptions struct {
+	Since  time.Time // 只返回这个时间（含）之后的日志，零值表示不限制
+	Until  time.Time // 只返回这个时间（含）之前的日志，零值表示不限制
+	Tail   int       // 只返回最后 N 行，0 表示不限制
+	Follow bool      // 读完已有内容后继续跟随新写入的日志，类似 tail -f
+}
+
+// logSource 描述一份可供读取的历史日志文件：可能是当前活跃文件，
+// 也可能是未压缩或已经 gzip 压缩的轮转备份。
+type logSource struct {
+	path       string
+	compressed bool
+}
+
+// LogReader 跨越当前活跃文件与所有轮转备份（含 gzip 压缩），
+// 按时间顺序重放某个服务此前写下的日志，用法类似 `docker logs`。
+// rootDir 下可能存在按时间分层的子目录（见 NewLogFile 的 filenamePattern），
+// 所以这里不假设日志都在同一层目录里。
+type LogReader struct {
+	serviceName string
+	rootDir     string
+}
+
+// NewLogReader 为 serviceName 创建一个读取器，日志目录的布局需要与 NewLogFile 写入时保持一致。
+func NewLogReader(serviceName string) (*LogReader, error) {
+	rootDir := filepath.Join(*logPath, serviceName)
+	if _, err := os.Stat(rootDir); err != nil {
+		return nil, fmt.Errorf("stat log dir %s error: %v", rootDir, err)
+	}
+	return &LogReader{serviceName: serviceName, rootDir: rootDir}, nil
+}
+
+// sources 递归枚举该服务所有的日志文件（含按时间分层的子目录），按时间从旧到新排序。
+// 能从文件名解析出轮转时间戳的算作历史备份；解析不出来的按 mtime 排序，其中最新的一份
+// 就是当前仍在写入的活跃文件，一并作为 active 返回供 follow 使用。
+func (r *LogReader) sources() (srcs []logSource, active string, err error) {
+	type timedSource struct {
+		src   logSource
+		t     time.Time
+		timed bool
+	}
+
+	canonical := filepath.Join(r.rootDir, r.serviceName+".log")
+
+	var all []timedSource
+	walkErr := filepath.WalkDir(r.rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), tmpLogfileSuffix) {
+			return nil
+		}
+
+		compressed := strings.HasSuffix(d.Name(), ".gz")
+		if t, ok := parseBackupTime(canonical, d.Name()); ok {
+			all = append(all, timedSource{src: logSource{path: path, compressed: compressed}, t: t, timed: true})
+			return nil
+		}
+
+		info, ierr := d.Info()
+		if ierr != nil {
+			return ierr
+		}
+		all = append(all, timedSource{src: logSource{path: path, compressed: compressed}, t: info.ModTime()})
+		return nil
+	})
+	if walkErr != nil {
+		return nil, "", fmt.Errorf("walk dir %s error: %v", r.rootDir, walkErr)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].t.Before(all[j].t) })
+
+	srcs = make([]logSource, 0, len(all))
+	for _, s := range all {
+		srcs = append(srcs, s.src)
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if !all[i].timed && !all[i].src.compressed {
+			active = all[i].src.path
+			break
+		}
+	}
+	return srcs, active, nil
+}
+
+func (s logSource) open() (io.ReadCloser, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if !s.compressed {
+		return f, nil
+	}
+
+	g, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: g, f: f}, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// lineTimestamp 解析出一行日志开头的 RFC3339Nano 时间戳，这是 Docker `Timestamps: true` 写入的格式。
+func lineTimestamp(line string) (time.Time, bool) {
+	sp := strings.IndexByte(line, ' ')
+	if sp <= 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, line[:sp])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Read 按 opts 过滤并返回日志内容，调用方读完后必须 Close。
+func (r *LogReader) Read(ctx context.Context, opts ReadOptions) (io.ReadCloser, error) {
+	sources, _, err := r.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(r.stream(ctx, sources, opts, pw))
+	}()
+	return pr, nil
+}
+
+func (r *LogReader) stream(ctx context.Context, sources []logSource, opts ReadOptions, w io.Writer) error {
+	lines, err := r.collectLines(sources, opts)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+
+	if !opts.Follow {
+		return nil
+	}
+	return r.follow(ctx, w)
+}
+
+// collectLines 读取所有来源里符合 Since/Until 的行；当只要求 Tail 且没有时间过滤时，
+// 从最新的文件开始反向扫描，只取最后 N 行，避免把全部历史读进内存。
+func (r *LogReader) collectLines(sources []logSource, opts ReadOptions) ([]string, error) {
+	if opts.Tail > 0 && opts.Since.IsZero() && opts.Until.IsZero() {
+		return r.tailLines(sources, opts.Tail)
+	}
+
+	var lines []string
+	for _, s := range sources {
+		sourceLines, err := readAllLines(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range sourceLines {
+			if t, ok := lineTimestamp(line); ok {
+				if !opts.Since.IsZero() && t.Before(opts.Since) {
+					continue
+				}
+				if !opts.Until.IsZero() && t.After(opts.Until) {
+					continue
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if opts.Tail > 0 && len(lines) > opts.Tail {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+	return lines, nil
+}
+
+// tailLines 对 ReadLastLine 的泛化：从最新文件往旧文件方向倒着取，凑够 n 行为止。
+// 每个来源只用常数级内存扫描（见 tailLastLines），不会把整份文件读进内存。
+func (r *LogReader) tailLines(sources []logSource, n int) ([]string, error) {
+	var lines []string
+	for i := len(sources) - 1; i >= 0 && len(lines) < n; i-- {
+		content, err := tailLastLines(sources[i], n-len(lines))
+		if err != nil {
+			return nil, err
+		}
+		lines = append(content, lines...)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// tailLastLines 返回来源 s 的最后 n 行。未压缩文件可以像 ReadLastLine 一样反向 seek，
+// 这里把它泛化成按块（而不是逐字节）反向扫描；gzip 压缩的归档没法反向 seek，只能顺序解压，
+// 但用一个大小为 n 的环形缓冲滚动保留最后 n 行，同样不需要把整份文件都放进内存。
+func tailLastLines(s logSource, n int) ([]string, error) {
+	if s.compressed {
+		return tailLastLinesForward(s, n)
+	}
+	return tailLastLinesBackward(s.path, n)
+}
+
+// tailChunkSize 是 tailLastLinesBackward 每次反向读取的块大小。
+const tailChunkSize = 64 * 1024
+
+// tailLastLinesBackward 从文件末尾开始按块向前扫描，找出最后 n 行，内存占用只取决于
+// n 和块大小，与文件总大小无关。
+func tailLastLinesBackward(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s error: %v", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s error: %v", path, err)
+	}
+
+	size := info.Size()
+	buf := make([]byte, tailChunkSize)
+
+	var lines []string
+	var carry []byte // 还没被换行符切出一整行的尾部字节（按时间顺序在 carry 右侧更靠后）
+	offset := size
+
+	for offset > 0 && len(lines) < n {
+		readSize := int64(tailChunkSize)
+		if readSize > offset {
+			readSize = offset
+		}
+		offset -= readSize
+
+		if _, err := f.ReadAt(buf[:readSize], offset); err != nil {
+			return nil, fmt.Errorf("read %s error: %v", path, err)
+		}
+
+		data := make([]byte, 0, int(readSize)+len(carry))
+		data = append(data, buf[:readSize]...)
+		data = append(data, carry...)
+
+		// 文件末尾通常自带一个换行符，第一块需要先去掉它，否则会被当成一行空行。
+		if offset+readSize == size {
+			data = bytes.TrimSuffix(data, []byte("\n"))
+		}
+
+		for len(lines) < n {
+			idx := bytes.LastIndexByte(data, '\n')
+			if idx < 0 {
+				break
+			}
+			lines = append([]string{string(data[idx+1:]) + "\n"}, lines...)
+			data = data[:idx]
+		}
+		carry = data
+	}
+
+	if len(lines) < n && len(carry) > 0 {
+		lines = append([]string{string(carry) + "\n"}, lines...)
+	}
+
+	return lines, nil
+}
+
+// tailLastLinesForward 用于无法反向 seek 的 gzip 归档：顺序解压整份文件，但只用一个
+// 大小为 n 的环形缓冲滚动保留最后 n 行，不会把解压出来的全部内容都留在内存里。
+func tailLastLinesForward(s logSource, n int) ([]string, error) {
+	rc, err := s.open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s error: %v", s.path, err)
+	}
+	defer rc.Close()
+
+	ring := make([]string, 0, n)
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text() + "\n"
+		if len(ring) < n {
+			ring = append(ring, line)
+		} else if n > 0 {
+			copy(ring, ring[1:])
+			ring[n-1] = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s error: %v", s.path, err)
+	}
+	return ring, nil
+}
+
+func readAllLines(s logSource) ([]string, error) {
+	rc, err := s.open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s error: %v", s.path, err)
+	}
+	defer rc.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text()+"\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s error: %v", s.path, err)
+	}
+	return lines, nil
+}
+
+// follow 在读完历史内容后继续跟随当前活跃文件的新增写入。日志可能散落在按时间分层的
+// 子目录里（见 filenamePattern），所以这里递归监听 rootDir 下的所有子目录，新建的子目录
+// 会被动态加入监听；每次收到 Create/Rename 事件都会重新核对一遍"当前活跃文件"，变化了就切换过去，
+// 这样无论是按大小轮转还是跨过时间边界都能正确衔接上。
+func (r *LogReader) follow(ctx context.Context, w io.Writer) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher error: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := r.watchTree(watcher); err != nil {
+		return err
+	}
+
+	_, active, err := r.sources()
+	if err != nil {
+		return err
+	}
+
+	var f *os.File
+	var reader *bufio.Reader
+	if active != "" {
+		if f, err = os.Open(active); err != nil {
+			return fmt.Errorf("open %s error: %v", active, err)
+		}
+		defer f.Close()
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+		reader = bufio.NewReader(f)
+	}
+
+	for {
+		if reader != nil {
+			for {
+				line, err := reader.ReadString('\n')
+				if len(line) > 0 {
+					if _, werr := io.WriteString(w, line); werr != nil {
+						return werr
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("监听日志目录出错", "dir", r.rootDir, "error", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op&fsnotify.Create != 0 {
+				if info, serr := os.Stat(event.Name); serr == nil && info.IsDir() {
+					// os.MkdirAll 可能在一次轮转里连续建出好几层新目录，深层的目录
+					// 在我们处理完这条事件、补上 watch 之前就可能已经创建好了，
+					// 所以干脆重新整棵树同步一遍 watch，而不是只加这一层。
+					if werr := r.watchTree(watcher); werr != nil {
+						slog.Warn("监听新建目录失败", "dir", event.Name, "error", werr)
+					}
+					continue
+				}
+			}
+
+			// 活跃文件每次 Write 都会触发一个 Write 事件，这里只关心可能意味着轮转的
+			// Create/Rename（新文件被建出来 / 旧文件被改名挪走），否则每写一行日志就要
+			// 递归扫一遍整棵目录树，代价太大。
+			if event.Op&(fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			_, newActive, serr := r.sources()
+			if serr != nil {
+				slog.Warn("重新扫描日志目录失败", "dir", r.rootDir, "error", serr)
+				continue
+			}
+			if newActive == active {
+				continue
+			}
+
+			slog.Info("检测到日志文件轮转，切换到新文件", "from", active, "to", newActive)
+			if f != nil {
+				f.Close()
+			}
+			active = newActive
+			if active == "" {
+				f, reader = nil, nil
+				continue
+			}
+			newF, oerr := os.Open(active)
+			if oerr != nil {
+				slog.Warn("重新打开日志文件失败", "logfile", active, "error", oerr)
+				f, reader = nil, nil
+				continue
+			}
+			f = newF
+			reader = bufio.NewReader(f)
+		}
+	}
+}
+
+// watchTree 递归地把 rootDir 以及其下所有子目录加入 watcher，fsnotify 本身不支持递归监听。
+func (r *LogReader) watchTree(watcher *fsnotify.Watcher) error {
+	return filepath.WalkDir(r.rootDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if werr := watcher.Add(path); werr != nil {
+				return fmt.Errorf("watch dir %s error: %v", path, werr)
+			}
+		}
+		return nil
+	})
+}
+
+// startLogServer 启动一个简单的 HTTP 接口：
+// GET /logs/{container}?since=&until=&tail=&follow= 以纯文本流式返回日志，免得直接碰磁盘。
+func startLogServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logs/", handleLogsRequest)
+
+	slog.Info("启动日志查询接口", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("日志查询接口退出", "addr", addr, "error", err)
+	}
+}
+
+func handleLogsRequest(w http.ResponseWriter, req *http.Request) {
+	container := strings.TrimPrefix(req.URL.Path, "/logs/")
+	if container == "" {
+		http.Error(w, "missing container name", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseReadOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reader, err := NewLogReader(container)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rc, err := reader.Read(req.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				slog.Warn("读取日志流失败", "container", container, "error", err)
+			}
+			return
+		}
+	}
+}
+
+func parseReadOptions(q url.Values) (ReadOptions, error) {
+	var opts ReadOptions
+
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return opts, fmt.Errorf("invalid since: %v", err)
+		}
+		opts.Since = t
+	}
+
+	if until := q.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339Nano, until)
+		if err != nil {
+			return opts, fmt.Errorf("invalid until: %v", err)
+		}
+		opts.Until = t
+	}
+
+	if tail := q.Get("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil {
+			return opts, fmt.Errorf("invalid tail: %v", err)
+		}
+		opts.Tail = n
+	}
+
+	opts.Follow = q.Get("follow") == "1" || q.Get("follow") == "true"
+
+	return opts, nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Rehtt/Kit/util/size"
+)
+
+var (
+	sinksFlag      = flag.String("sinks", "file", "comma-separated output sinks to enable: file,syslog,journald,loki,kafka")
+	sinkConfigPath = flag.String("sink-config", "", "path to a JSON file with per-sink options (see sinksConfig)")
+)
+
+// Entry 是从 Docker 多路复用流中解出的一行日志，携带来源容器、流类型（stdout/stderr）
+// 和 Docker 自身打上的时间戳。
+type Entry struct {
+	Container string
+	Stream    string
+	Time      time.Time
+	Message   []byte
+}
+
+// Sink 是一个日志输出后端，FileSink 是默认实现，其余（syslog/journald/loki/kafka）都是可选的。
+type Sink interface {
+	Write(entry Entry) error
+	Close() error
+}
+
+// sinksConfig 是 -sink-config 指向的 JSON 文件的结构，每个 sink 的配置都是可选的，
+// 未出现的字段回退到对应的命令行参数或内置默认值。
+type sinksConfig struct {
+	File     *fileSinkConfig     `json:"file,omitempty"`
+	Syslog   *syslogSinkConfig   `json:"syslog,omitempty"`
+	Journald *journaldSinkConfig `json:"journald,omitempty"`
+	Loki     *lokiSinkConfig     `json:"loki,omitempty"`
+	Kafka    *kafkaSinkConfig    `json:"kafka,omitempty"`
+}
+
+func loadSinksConfig(path string) (*sinksConfig, error) {
+	cfg := &sinksConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sink config %s error: %v", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse sink config %s error: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// buildSinks 根据 -sinks 和 -sink-config 为 name 创建并包装好所有输出 sink。
+// 返回的 fileSink（若启用了 file sink）供调用方用来确定续传位置。
+func buildSinks(name string, limit size.ByteSize) (sinks []Sink, fileSink *FileSink, err error) {
+	cfg, err := loadSinksConfig(*sinkConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, kind := range strings.Split(*sinksFlag, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+
+		var sink Sink
+		switch kind {
+		case "file":
+			fs, ferr := newFileSink(name, limit, cfg.File)
+			if ferr != nil {
+				err = ferr
+			} else {
+				fileSink = fs
+				sink = fs
+			}
+		case "syslog":
+			sink, err = newSyslogSink(cfg.Syslog)
+		case "journald":
+			sink, err = newJournaldSink(cfg.Journald)
+		case "loki":
+			sink, err = newLokiSink(name, cfg.Loki)
+		case "kafka":
+			sink, err = newKafkaSink(cfg.Kafka)
+		default:
+			err = fmt.Errorf("unknown sink %q", kind)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("init sink %q error: %v", kind, err)
+		}
+
+		if kind == "file" {
+			// file sink 必须保持同步且不丢数据：Since 续传、保留期清理、崩溃恢复
+			// 压缩全都假设磁盘上的日志是权威且完整的，丢行会破坏这些前提。
+			// 只有远程/慢速 sink 才需要 asyncSink 的隔离与丢弃策略。
+			sinks = append(sinks, sink)
+		} else {
+			sinks = append(sinks, newAsyncSink(kind, sink, 1024))
+		}
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil, fmt.Errorf("no sinks configured")
+	}
+	return sinks, fileSink, nil
+}
+
+// asyncSink 把 Write 与实际落地解耦到一个有缓冲 channel 背后的 goroutine，
+// 队列满时丢弃并告警，避免某个写得慢的远程 sink（比如网络抖动的 Loki）拖慢文件写入等其它 sink。
+type asyncSink struct {
+	name string
+	sink Sink
+	ch   chan Entry
+	done chan struct{}
+}
+
+func newAsyncSink(name string, sink Sink, buffer int) *asyncSink {
+	a := &asyncSink{name: name, sink: sink, ch: make(chan Entry, buffer), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *asyncSink) run() {
+	defer close(a.done)
+	for e := range a.ch {
+		if err := a.sink.Write(e); err != nil {
+			slog.Error("写入 sink 失败", "sink", a.name, "container", e.Container, "error", err)
+		}
+	}
+}
+
+func (a *asyncSink) Write(e Entry) error {
+	select {
+	case a.ch <- e:
+	default:
+		slog.Warn("sink 队列已满，丢弃日志条目", "sink", a.name, "container", e.Container)
+	}
+	return nil
+}
+
+func (a *asyncSink) Close() error {
+	close(a.ch)
+	<-a.done
+	return a.sink.Close()
+}
+
+// fileSinkConfig 未设置的字段回退到对应的 -compression/-max-age/-max-backups/-local-time/
+// -rotate-interval/-filename-pattern 参数。
+type fileSinkConfig struct {
+	Compression     *bool          `json:"compression,omitempty"`
+	MaxAge          *time.Duration `json:"max_age,omitempty"`
+	MaxBackups      *int           `json:"max_backups,omitempty"`
+	LocalTime       *bool          `json:"local_time,omitempty"`
+	RotateInterval  *string        `json:"rotate_interval,omitempty"`
+	FilenamePattern *string        `json:"filename_pattern,omitempty"`
+}
+
+// FileSink 是默认 sink，把日志写入 LogFile 管理的按大小/时间轮转文件。
+type FileSink struct {
+	lf *LogFile
+}
+
+func newFileSink(name string, limit size.ByteSize, cfg *fileSinkConfig) (*FileSink, error) {
+	compress, maxAgeV, maxBackupsV, localTimeV := *compression, *maxAge, *maxBackups, *localTime
+	rotateIntervalRaw, filenamePatternV := *rotateInterval, *filenamePattern
+	if cfg != nil {
+		if cfg.Compression != nil {
+			compress = *cfg.Compression
+		}
+		if cfg.MaxAge != nil {
+			maxAgeV = *cfg.MaxAge
+		}
+		if cfg.MaxBackups != nil {
+			maxBackupsV = *cfg.MaxBackups
+		}
+		if cfg.LocalTime != nil {
+			localTimeV = *cfg.LocalTime
+		}
+		if cfg.RotateInterval != nil {
+			rotateIntervalRaw = *cfg.RotateInterval
+		}
+		if cfg.FilenamePattern != nil {
+			filenamePatternV = *cfg.FilenamePattern
+		}
+	}
+
+	rotateIntervalV, err := parseRotateInterval(rotateIntervalRaw)
+	if err != nil {
+		return nil, fmt.Errorf("parse rotate interval %q error: %v", rotateIntervalRaw, err)
+	}
+
+	lf, err := NewLogFile(name, limit, compress, maxAgeV, maxBackupsV, localTimeV, rotateIntervalV, filenamePatternV)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{lf: lf}, nil
+}
+
+func (s *FileSink) Write(e Entry) error {
+	_, err := s.lf.Write([]byte(fmt.Sprintf("%s %s\n", e.Time.Format(time.RFC3339Nano), e.Message)))
+	return err
+}
+
+func (s *FileSink) Close() error { return s.lf.Close() }
+
+// lastLine 返回文件中最后一行，供 handleWithContext 计算 ContainerLogs 的 Since 续传位置。
+func (s *FileSink) lastLine() (string, error) { return ReadLastLine(s.lf.f) }
+
+// lineWriter 把 stdcopy.StdCopy 解复用出来的某一路（stdout 或 stderr）字节流
+// 按行切分，解析出 Docker 前缀的时间戳后分发给所有配置的 sink。
+type lineWriter struct {
+	container string
+	stream    string
+	sinks     []Sink
+	buf       []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf[:idx]
+		w.buf = w.buf[idx+1:]
+		w.dispatch(line)
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) dispatch(line []byte) {
+	t, msg := splitTimestamp(line)
+	entry := Entry{Container: w.container, Stream: w.stream, Time: t, Message: msg}
+	for _, s := range w.sinks {
+		if err := s.Write(entry); err != nil {
+			slog.Error("写入 sink 失败", "container", w.container, "stream", w.stream, "error", err)
+		}
+	}
+}
+
+// splitTimestamp 把 Docker `Timestamps: true` 打在行首的 RFC3339Nano 时间戳与消息正文分开。
+func splitTimestamp(line []byte) (time.Time, []byte) {
+	sp := bytes.IndexByte(line, ' ')
+	if sp <= 0 {
+		return time.Now(), line
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(line[:sp]))
+	if err != nil {
+		return time.Now(), line
+	}
+	return t, line[sp+1:]
+}
@@ -0,0 +1,72 @@
+//go:build linux && cgo && journald
+
+package main
+
+/*
+#cgo LDFLAGS: -lsystemd
+#include <systemd/sd-journal.h>
+#include <stdlib.h>
+
+static int journal_sendv(const struct iovec *iv, int n) {
+	return sd_journal_sendv(iv, n);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// journaldSinkConfig 描述写入 journald 时使用的标识符。
+type journaldSinkConfig struct {
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// JournaldSink 通过 sd_journal_sendv 把日志直接写入本机的 systemd-journald。
+type JournaldSink struct {
+	identifier string
+}
+
+func newJournaldSink(cfg *journaldSinkConfig) (*JournaldSink, error) {
+	identifier := "docker-logs"
+	if cfg != nil && cfg.Identifier != "" {
+		identifier = cfg.Identifier
+	}
+	return &JournaldSink{identifier: identifier}, nil
+}
+
+func (s *JournaldSink) Write(e Entry) error {
+	priority := "6"
+	if e.Stream == "stderr" {
+		priority = "3"
+	}
+
+	fields := []string{
+		"MESSAGE=" + string(e.Message),
+		"PRIORITY=" + priority,
+		"SYSLOG_IDENTIFIER=" + s.identifier,
+		"CONTAINER_NAME=" + e.Container,
+		"CONTAINER_STREAM=" + e.Stream,
+	}
+
+	iov := make([]C.struct_iovec, len(fields))
+	cstrs := make([]*C.char, len(fields))
+	for i, f := range fields {
+		cstrs[i] = C.CString(f)
+		iov[i].iov_base = unsafe.Pointer(cstrs[i])
+		iov[i].iov_len = C.size_t(len(f))
+	}
+	defer func() {
+		for _, cs := range cstrs {
+			C.free(unsafe.Pointer(cs))
+		}
+	}()
+
+	if ret := C.journal_sendv(&iov[0], C.int(len(iov))); ret < 0 {
+		return fmt.Errorf("sd_journal_sendv failed: %d", ret)
+	}
+	return nil
+}
+
+func (s *JournaldSink) Close() error { return nil }
@@ -0,0 +1,16 @@
+//go:build !(linux && cgo && journald)
+
+package main
+
+import "fmt"
+
+// journaldSinkConfig mirrors the cgo-backed definition so sink.go compiles on
+// platforms (or CGO_ENABLED=0 builds, or builds without -tags journald) where
+// journald isn't available.
+type journaldSinkConfig struct {
+	Identifier string `json:"identifier,omitempty"`
+}
+
+func newJournaldSink(cfg *journaldSinkConfig) (Sink, error) {
+	return nil, fmt.Errorf("journald sink is only supported on linux with cgo enabled, built with -tags journald")
+}
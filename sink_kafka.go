@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaSinkConfig 描述要推送到哪个 Kafka topic。
+type kafkaSinkConfig struct {
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+}
+
+type kafkaEntry struct {
+	Container string    `json:"container"`
+	Stream    string    `json:"stream"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+}
+
+// KafkaSink 把每条日志编码成 JSON，以容器名为 key 写入指定的 Kafka topic。
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink(cfg *kafkaSinkConfig) (*KafkaSink, error) {
+	if cfg == nil || len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka sink requires brokers and topic")
+	}
+
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(cfg.Brokers...),
+			Topic:    cfg.Topic,
+			Balancer: &kafka.LeastBytes{},
+		},
+	}, nil
+}
+
+func (s *KafkaSink) Write(e Entry) error {
+	payload, err := json.Marshal(kafkaEntry{
+		Container: e.Container,
+		Stream:    e.Stream,
+		Time:      e.Time,
+		Message:   string(e.Message),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal kafka message error: %v", err)
+	}
+
+	return s.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(e.Container),
+		Value: payload,
+	})
+}
+
+func (s *KafkaSink) Close() error { return s.writer.Close() }
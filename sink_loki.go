@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lokiSinkConfig 描述批量推送到 Loki 的目标和批处理参数。
+type lokiSinkConfig struct {
+	URL           string            `json:"url"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	BatchSize     int               `json:"batch_size,omitempty"`     // 默认 100
+	FlushInterval time.Duration     `json:"flush_interval,omitempty"` // 默认 2s
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+// LokiSink 把日志按 container/stream 分组，批量 POST 到 Loki 的 /loki/api/v1/push。
+type LokiSink struct {
+	url       string
+	labels    map[string]string
+	batchSize int
+	client    *http.Client
+
+	mu      sync.Mutex
+	entries []Entry
+
+	flushCh chan struct{}
+	closeCh chan struct{}
+	done    chan struct{}
+}
+
+func newLokiSink(container string, cfg *lokiSinkConfig) (*LokiSink, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, fmt.Errorf("loki sink requires url")
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 2 * time.Second
+	}
+
+	labels := map[string]string{"container": container}
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+
+	s := &LokiSink{
+		url:       cfg.URL,
+		labels:    labels,
+		batchSize: batchSize,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		flushCh:   make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.flushLoop(flushInterval)
+	return s, nil
+}
+
+func (s *LokiSink) Write(e Entry) error {
+	s.mu.Lock()
+	s.entries = append(s.entries, e)
+	full := len(s.entries) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *LokiSink) flushLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.flushCh:
+			s.flush()
+		case <-s.closeCh:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush 把积压的条目按 container/stream 分组后一次性推给 Loki，
+// 因为同一个 stream 下的所有 value 必须共享同一组 label。
+func (s *LokiSink) flush() {
+	s.mu.Lock()
+	batch := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	grouped := make(map[string][][2]string)
+	for _, e := range batch {
+		key := e.Container + "/" + e.Stream
+		grouped[key] = append(grouped[key], [2]string{
+			strconv.FormatInt(e.Time.UnixNano(), 10),
+			string(e.Message),
+		})
+	}
+
+	var push lokiPushRequest
+	for key, values := range grouped {
+		container, stream, _ := strings.Cut(key, "/")
+		labels := make(map[string]string, len(s.labels)+2)
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		labels["container"] = container
+		labels["stream"] = stream
+		push.Streams = append(push.Streams, lokiStream{Stream: labels, Values: values})
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		slog.Error("序列化 loki 推送请求失败", "error", err)
+		return
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Error("推送日志到 loki 失败", "url", s.url, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		slog.Error("loki 返回错误状态码", "url", s.url, "status", resp.StatusCode)
+	}
+}
+
+func (s *LokiSink) Close() error {
+	close(s.closeCh)
+	<-s.done
+	return nil
+}
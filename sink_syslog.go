@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// syslogSinkConfig 描述如何连接一个 RFC5424 syslog 接收端。
+type syslogSinkConfig struct {
+	Network               string `json:"network"` // udp、tcp 或 tcp+tls，默认 udp
+	Addr                  string `json:"addr"`
+	Facility              int    `json:"facility,omitempty"` // 默认 1 (user-level messages)
+	AppName               string `json:"app_name,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify,omitempty"`
+}
+
+// SyslogSink 把日志以 RFC5424 格式通过 UDP/TCP/TLS 发给远端 syslog 接收端。
+type SyslogSink struct {
+	mu       sync.Mutex
+	conn     net.Conn
+	facility int
+	appName  string
+	hostname string
+}
+
+func newSyslogSink(cfg *syslogSinkConfig) (*SyslogSink, error) {
+	if cfg == nil || cfg.Addr == "" {
+		return nil, fmt.Errorf("syslog sink requires addr")
+	}
+
+	network := cfg.Network
+	if network == "" {
+		network = "udp"
+	}
+
+	var conn net.Conn
+	var err error
+	if network == "tcp+tls" {
+		conn, err = tls.Dial("tcp", cfg.Addr, &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify})
+	} else {
+		conn, err = net.Dial(network, cfg.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial syslog %s %s error: %v", network, cfg.Addr, err)
+	}
+
+	facility := cfg.Facility
+	if facility == 0 {
+		facility = 1
+	}
+	hostname, _ := os.Hostname()
+
+	return &SyslogSink{conn: conn, facility: facility, appName: cfg.AppName, hostname: hostname}, nil
+}
+
+func (s *SyslogSink) Write(e Entry) error {
+	severity := 6 // informational
+	if e.Stream == "stderr" {
+		severity = 3 // error
+	}
+	pri := s.facility*8 + severity
+
+	appName := s.appName
+	if appName == "" {
+		appName = e.Container
+	}
+
+	// RFC5424: <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - - %s\n",
+		pri, e.Time.Format(time.RFC3339Nano), s.hostname, appName, e.Message)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.conn.Write([]byte(msg))
+	return err
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.Close()
+}